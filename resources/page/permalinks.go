@@ -0,0 +1,768 @@
+// Copyright 2024 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package page
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gohugoio/hugo/source"
+)
+
+// timeLayoutComponentRe matches attribute names that are composed entirely
+// of Go reference-time layout components (and separators), e.g. "Monday",
+// "06" or "2006_01_02_15_04_05.000". It's used to recognize custom date
+// formats that aren't one of the dedicated date attributes below.
+var timeLayoutComponentRe = regexp.MustCompile(
+	`^(?:2006|06|January|Jan|Monday|Mon|15|01|02|03|04|05|000000000|000000|000|1|2|3|4|5|PM|pm|MST|[._:-])+$`)
+
+// Page is the minimal page contract the permalink expander needs. The full
+// page.Page implementation satisfies it, as does the lightweight test
+// double used in this package's tests.
+type Page interface {
+	Title() string
+	Date() time.Time
+	Section() string
+	SectionsEntries() []string
+	Slug() string
+	Kind() string
+	File() *source.File
+	Params() map[string]any
+
+	// ContentHashInput returns the bytes the :contenthash permalink
+	// attribute hashes: the page's rendered content where available, or
+	// its raw source bytes plus front matter otherwise.
+	ContentHashInput() ([]byte, error)
+}
+
+// attrArgs carries the optional slice/index suffix (e.g. "[last]") attached
+// to a single chain element.
+type attrArgs struct {
+	slice string
+}
+
+// pageToPermaAttribute resolves a single permalink attribute, e.g. "slug"
+// or "year", for the given page.
+type pageToPermaAttribute func(pe *PermalinkExpander, p Page, args attrArgs) (string, error)
+
+// chainElement is one `name` or `name[slice]` step of a fallback chain.
+type chainElement struct {
+	name  string
+	slice string
+}
+
+// rawSegment is either a literal run of text or a fallback chain parsed out
+// of a single `:attr` (or `:{a|b|c}`) occurrence in a pattern.
+type rawSegment struct {
+	literal string
+	chain   []chainElement
+	length  string // optional ":N" suffix, e.g. for :contenthash:8
+}
+
+// compiledSegment is a rawSegment with its chain resolved to callable
+// attribute functions, ready to be evaluated against a page.
+type compiledSegment struct {
+	literal string
+	chain   []func(Page) (string, error)
+}
+
+// PermalinkExpander expands permalink patterns, as found in a site's
+// permalinks configuration, into actual URLs for a given page.
+type PermalinkExpander struct {
+	urlize func(uri string) string
+
+	// knownPermalinkAttributes maps an attribute name to the function that
+	// resolves it.
+	knownPermalinkAttributes map[string]pageToPermaAttribute
+
+	// chainAliases maps legacy, hard-coded fallback tokens to the general
+	// fallback-chain expression they're equivalent to. They exist purely
+	// for backward compatibility; new patterns should spell out the chain,
+	// e.g. ":{slug|filename}". Unlike the bare ":slug" token (handled
+	// separately in parsePattern), these are resolved in resolveElement,
+	// so they also compose as a single element of a larger chain, e.g.
+	// ":{slugorfilename|title}".
+	chainAliases map[string]string
+
+	// expanders holds, per page kind and pattern name, the compiled
+	// expansion function for the patterns passed to NewPermalinkExpander.
+	expanders map[string]map[string]func(Page) (string, error)
+}
+
+// NewPermalinkExpander creates a new PermalinkExpander from the given
+// patterns, which is a map from page kind to a map from pattern name to
+// permalink pattern, e.g. patterns["page"]["posts"] == "/:year/:month/:slug/".
+func NewPermalinkExpander(urlize func(string) string, patterns map[string]map[string]string) (PermalinkExpander, error) {
+	pe := PermalinkExpander{
+		urlize: urlize,
+		chainAliases: map[string]string{
+			"slugorfilename":        "{slug|filename}",
+			"slugorcontentbasename": "{slug|contentbasename}",
+		},
+		knownPermalinkAttributes: map[string]pageToPermaAttribute{
+			"title":           attrTitle,
+			"section":         attrSection,
+			"slug":            attrSlug,
+			"filename":        attrFilename,
+			"contentbasename": attrContentBaseName,
+			"sections":        attrSections,
+			"unix":            attrUnix,
+			"unixhex":         attrUnixHex,
+			"unixhashed":      attrUnixHashed,
+			"year":            attrYear,
+			"month":           attrMonth,
+			"monthname":       attrMonthName,
+			"day":             attrDay,
+			"weekday":         attrWeekday,
+			"weekdayname":     attrWeekdayName,
+			"yearday":         attrYearDay,
+		},
+	}
+
+	expanders := make(map[string]map[string]func(Page) (string, error), len(patterns))
+	for kind, kindPatterns := range patterns {
+		compiled := make(map[string]func(Page) (string, error), len(kindPatterns))
+		for name, pattern := range kindPatterns {
+			fn, err := pe.compile(pattern)
+			if err != nil {
+				return PermalinkExpander{}, fmt.Errorf("invalid permalink pattern for %q (kind %q): %w", name, kind, err)
+			}
+			compiled[name] = fn
+		}
+		expanders[kind] = compiled
+	}
+	pe.expanders = expanders
+
+	return pe, nil
+}
+
+// Expand expands the configured pattern named name for the given page's
+// kind.
+func (pe PermalinkExpander) Expand(name string, p Page) (string, error) {
+	kindExpanders, ok := pe.expanders[p.Kind()]
+	if !ok {
+		return "", fmt.Errorf("permalinks: no patterns configured for kind %q", p.Kind())
+	}
+	fn, ok := kindExpanders[name]
+	if !ok {
+		return "", fmt.Errorf("permalinks: no pattern named %q for kind %q", name, p.Kind())
+	}
+	return fn(p)
+}
+
+// ExpandPattern expands pattern, which need not be part of the site's
+// permalinks configuration, for the given page.
+func (pe PermalinkExpander) ExpandPattern(pattern string, p Page) (string, error) {
+	fn, err := pe.compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return fn(p)
+}
+
+// compile parses and validates pattern, returning a function that expands
+// it for a given page. Resolving every attribute up front means an unknown
+// or malformed attribute is reported once, at configuration time, rather
+// than on every page.
+func (pe *PermalinkExpander) compile(pattern string) (func(Page) (string, error), error) {
+	parsed, err := pe.parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]compiledSegment, len(parsed))
+	for i, seg := range parsed {
+		if seg.chain == nil {
+			segments[i] = compiledSegment{literal: seg.literal}
+			continue
+		}
+		fns, err := pe.resolveChain(seg.chain, seg.length)
+		if err != nil {
+			return nil, fmt.Errorf("permalinks: invalid pattern %q: %w", pattern, err)
+		}
+		segments[i] = compiledSegment{chain: fns}
+	}
+
+	return func(p Page) (string, error) {
+		var sb strings.Builder
+		for _, seg := range segments {
+			if seg.chain == nil {
+				sb.WriteString(seg.literal)
+				continue
+			}
+			v, err := evalChain(seg.chain, p)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(v)
+		}
+		return sb.String(), nil
+	}, nil
+}
+
+// parsePattern splits pattern into a sequence of literal and fallback-chain
+// segments. A chain may be written as a single bare attribute (":slug"), a
+// pipe-separated fallback chain (":slug|filename"), or the braced form
+// (":{slug|filename}"). Elements may carry a slice suffix in either form,
+// e.g. ":sections[last]|section"; the braced form is required when a slice
+// contains a colon, e.g. ":{sections[1:]|section}", since a bare token ends
+// at the first colon. A backslash escapes a literal colon.
+func (pe *PermalinkExpander) parsePattern(pattern string) ([]rawSegment, error) {
+	var segments []rawSegment
+	var lit strings.Builder
+
+	flushLiteral := func() {
+		if lit.Len() > 0 {
+			segments = append(segments, rawSegment{literal: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	i := 0
+	for i < len(pattern) {
+		c := pattern[i]
+
+		if c == '\\' && i+1 < len(pattern) && pattern[i+1] == ':' {
+			lit.WriteByte(':')
+			i += 2
+			continue
+		}
+
+		if c != ':' {
+			lit.WriteByte(c)
+			i++
+			continue
+		}
+
+		flushLiteral()
+		i++
+
+		var rawChain string
+		if i < len(pattern) && pattern[i] == '{' {
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("permalinks: unterminated %q in pattern %q", "{", pattern)
+			}
+			rawChain = pattern[i+1 : i+end]
+			i += end + 1
+		} else {
+			start := i
+			for i < len(pattern) && isChainByte(pattern[i]) {
+				i++
+			}
+			rawChain = pattern[start:i]
+		}
+
+		if rawChain == "" {
+			return nil, fmt.Errorf("permalinks: empty attribute in pattern %q", pattern)
+		}
+
+		// An attribute may be followed directly by a ":N" length suffix,
+		// e.g. ":contenthash:8". It applies to the whole token, not to an
+		// individual chain element.
+		var length string
+		if i < len(pattern) && pattern[i] == ':' {
+			j := i + 1
+			for j < len(pattern) && pattern[j] >= '0' && pattern[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				length = pattern[i+1 : j]
+				i = j
+			}
+		}
+
+		if rawChain == "slug" {
+			// The bare ":slug" token has always fallen back to the title
+			// when no slug is set. That's purely a whole-token legacy
+			// behavior, so it's rewritten here rather than folded into
+			// chainAliases: "slug" must stay raw, with no fallback, when
+			// it appears as an element of a larger chain (e.g. inside
+			// the "slugorfilename" alias in NewPermalinkExpander).
+			rawChain = "slug|title"
+		}
+
+		elems, err := parseChain(rawChain)
+		if err != nil {
+			return nil, fmt.Errorf("permalinks: invalid pattern %q: %w", pattern, err)
+		}
+
+		segments = append(segments, rawSegment{chain: elems, length: length})
+	}
+	flushLiteral()
+
+	return segments, nil
+}
+
+func isChainByte(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	switch b {
+	case '.', '|', '[', ']', '_':
+		return true
+	}
+	return false
+}
+
+// parseChain splits raw (the content of a ":{...}" or bare ":a|b" token)
+// into its pipe-separated elements, each with its optional "[slice]"
+// suffix split off.
+func parseChain(raw string) ([]chainElement, error) {
+	parts := strings.Split(raw, "|")
+	elems := make([]chainElement, 0, len(parts))
+	for _, part := range parts {
+		name, slice := part, ""
+		// Only a bracket group trailing the whole element is a slice
+		// suffix; a "params.<path>" element may have brackets mid-path
+		// (e.g. "params.tags[0].name"), which attrParams parses itself.
+		if strings.HasSuffix(part, "]") {
+			if idx := strings.LastIndexByte(part, '['); idx >= 0 {
+				name, slice = part[:idx], part[idx:]
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty attribute name in %q", raw)
+		}
+		elems = append(elems, chainElement{name: name, slice: slice})
+	}
+	return elems, nil
+}
+
+// resolveChain resolves every element of chain to a callable attribute
+// function, erroring out on the first unknown or malformed one.
+func (pe *PermalinkExpander) resolveChain(chain []chainElement, length string) ([]func(Page) (string, error), error) {
+	fns := make([]func(Page) (string, error), len(chain))
+	for i, elem := range chain {
+		fn, err := pe.resolveElement(elem, length)
+		if err != nil {
+			return nil, err
+		}
+		fns[i] = fn
+	}
+	return fns, nil
+}
+
+func (pe *PermalinkExpander) resolveElement(elem chainElement, length string) (func(Page) (string, error), error) {
+	if elem.name == "contenthash" || elem.name == "contenthashhex" {
+		if elem.slice != "" {
+			return nil, fmt.Errorf("%q does not take a slice argument", elem.name)
+		}
+		n := 0
+		if length != "" {
+			v, err := strconv.Atoi(length)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid length %q for %q", length, elem.name)
+			}
+			n = v
+		}
+		asHex := elem.name == "contenthashhex"
+		return func(p Page) (string, error) { return pe.contentHash(p, n, asHex) }, nil
+	}
+
+	if length != "" {
+		return nil, fmt.Errorf("%q does not take a length argument", elem.name)
+	}
+
+	if alias, ok := pe.chainAliases[elem.name]; ok {
+		if elem.slice != "" {
+			return nil, fmt.Errorf("%q does not take a slice argument", elem.name)
+		}
+		aliasChain, err := parseChain(strings.TrimSuffix(strings.TrimPrefix(alias, "{"), "}"))
+		if err != nil {
+			return nil, err
+		}
+		fns, err := pe.resolveChain(aliasChain, "")
+		if err != nil {
+			return nil, err
+		}
+		return func(p Page) (string, error) { return evalChain(fns, p) }, nil
+	}
+
+	if strings.HasPrefix(elem.name, "params.") {
+		path := strings.TrimPrefix(elem.name, "params.")
+		if path == "" {
+			return nil, fmt.Errorf("empty params path in %q", elem.name)
+		}
+		args := attrArgs{slice: elem.slice}
+		return func(p Page) (string, error) { return pe.attrParams(p, path, args) }, nil
+	}
+
+	if attr, ok := pe.knownPermalinkAttributes[elem.name]; ok {
+		args := attrArgs{slice: elem.slice}
+		return func(p Page) (string, error) { return attr(pe, p, args) }, nil
+	}
+
+	if elem.slice != "" {
+		return nil, fmt.Errorf("%q does not take a slice argument", elem.name)
+	}
+
+	if timeLayoutComponentRe.MatchString(elem.name) {
+		layout := elem.name
+		return func(p Page) (string, error) { return p.Date().Format(layout), nil }, nil
+	}
+
+	return nil, fmt.Errorf("unknown attribute %q", elem.name)
+}
+
+// evalChain evaluates fns in order against p, returning the first
+// non-empty result. An error from any element aborts the chain.
+func evalChain(fns []func(Page) (string, error), p Page) (string, error) {
+	for _, fn := range fns {
+		v, err := fn(p)
+		if err != nil {
+			return "", err
+		}
+		if v != "" {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// toSliceFunc parses a "[slice]" expression -- "[n]", "[a:b]", "[a:]",
+// "[:b]", "[:]", "[last]" or "[:last]" -- into a function that applies it
+// to a []string. An empty spec is the identity function. An invalid spec
+// yields a function that always returns nil.
+func (pe *PermalinkExpander) toSliceFunc(spec string) func([]string) []string {
+	invalid := func([]string) []string { return nil }
+
+	if spec == "" {
+		return func(s []string) []string { return s }
+	}
+	if !strings.HasPrefix(spec, "[") || !strings.HasSuffix(spec, "]") {
+		return invalid
+	}
+
+	inner := spec[1 : len(spec)-1]
+
+	if inner == "last" {
+		return func(s []string) []string {
+			if len(s) == 0 {
+				return nil
+			}
+			return s[len(s)-1:]
+		}
+	}
+
+	parts := strings.SplitN(inner, ":", 2)
+
+	if len(parts) == 1 {
+		i, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return invalid
+		}
+		return func(s []string) []string {
+			if i < 0 || i >= len(s) {
+				return []string{}
+			}
+			return s[i : i+1]
+		}
+	}
+
+	from, to := parts[0], parts[1]
+
+	return func(s []string) []string {
+		if len(s) == 0 {
+			return nil
+		}
+
+		start, end := 0, len(s)
+
+		if to == "last" {
+			end = len(s) - 1
+		} else if to != "" {
+			n, err := strconv.Atoi(to)
+			if err != nil {
+				return nil
+			}
+			end = n
+		}
+
+		if from != "" {
+			n, err := strconv.Atoi(from)
+			if err != nil {
+				return nil
+			}
+			start = n
+		}
+
+		if start < 0 {
+			start = 0
+		}
+		if end > len(s) {
+			end = len(s)
+		}
+		if start >= end {
+			return []string{}
+		}
+		return s[start:end]
+	}
+}
+
+func attrTitle(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return pe.urlize(p.Title()), nil
+}
+
+func attrSection(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return pe.urlize(p.Section()), nil
+}
+
+func attrSlug(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return pe.urlize(p.Slug()), nil
+}
+
+// attrFilename returns the URL-safe form of the file's translation base
+// name, e.g. "test-page" for "test-page.md". For a page bundle (the file is
+// literally named "index"), the containing directory's name is used
+// instead, since "index" itself wouldn't make for a useful URL segment; for
+// the bundle's "_index" (section/branch) variant, there's no good fallback,
+// so it expands to empty.
+func attrFilename(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	f := p.File()
+	if f == nil {
+		return "", nil
+	}
+	name := f.TranslationBaseName()
+	switch name {
+	case "index":
+		dir := strings.TrimSuffix(f.Dir(), "/")
+		name = path.Base(dir)
+	case "_index":
+		return "", nil
+	}
+	return pe.urlize(name), nil
+}
+
+func attrContentBaseName(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	f := p.File()
+	if f == nil {
+		return "", nil
+	}
+	return pe.urlize(f.ContentBaseName()), nil
+}
+
+func attrSections(pe *PermalinkExpander, p Page, args attrArgs) (string, error) {
+	sections := pe.toSliceFunc(args.slice)(p.SectionsEntries())
+	urlized := make([]string, len(sections))
+	for i, s := range sections {
+		urlized[i] = pe.urlize(s)
+	}
+	return strings.Join(urlized, "/"), nil
+}
+
+func attrUnix(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return strconv.FormatInt(p.Date().Unix(), 10), nil
+}
+
+func attrUnixHex(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return fmt.Sprintf("%x", p.Date().Unix()), nil
+}
+
+// attrUnixHashed hashes the page's unix date together with the
+// "datepepper" front matter parameter, if any, giving an identifier that
+// changes unpredictably with the date without exposing it directly.
+func attrUnixHashed(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d", p.Date().Unix())
+	h.Write([]byte{0})
+	h.Write([]byte(pepperParam(p, "datepepper")))
+	sum := h.Sum(nil)
+	return hex.EncodeToString(sum[4:8]), nil
+}
+
+// contentHash computes a SHA-256 digest of p's content, honoring the
+// "contenthashpepper" front matter parameter for domain separation the same
+// way attrUnixHashed honors "datepepper". n, when non-zero, truncates the
+// result to n characters. asHex selects hex over the more compact,
+// URL-safe base32 encoding used by :contenthash.
+func (pe *PermalinkExpander) contentHash(p Page, n int, asHex bool) (string, error) {
+	input, err := p.ContentHashInput()
+	if err != nil {
+		return "", fmt.Errorf("permalinks: failed to read content for hashing: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write(input)
+	h.Write([]byte{0})
+	h.Write([]byte(pepperParam(p, "contenthashpepper")))
+	sum := h.Sum(nil)
+
+	var out string
+	if asHex {
+		out = hex.EncodeToString(sum)
+	} else {
+		out = strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum))
+	}
+
+	if n > 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out, nil
+}
+
+func attrYear(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return p.Date().Format("2006"), nil
+}
+
+func attrMonth(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return p.Date().Format("01"), nil
+}
+
+func attrMonthName(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return p.Date().Format("January"), nil
+}
+
+func attrDay(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return p.Date().Format("02"), nil
+}
+
+func attrWeekday(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return strconv.Itoa(int(p.Date().Weekday())), nil
+}
+
+func attrWeekdayName(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return p.Date().Format("Monday"), nil
+}
+
+func attrYearDay(pe *PermalinkExpander, p Page, _ attrArgs) (string, error) {
+	return strconv.Itoa(p.Date().YearDay()), nil
+}
+
+// pepperParam returns the string value of the given front matter parameter,
+// or the empty string if it's absent or not a string.
+func pepperParam(p Page, key string) string {
+	v, _ := p.Params()[key].(string)
+	return v
+}
+
+// attrParams resolves a "params.<path>" attribute by walking the page's
+// front matter parameters along a dot-separated, case-insensitive path,
+// e.g. "category", "author.name" or "tags[last]". A missing or nil value
+// anywhere along the path expands to the empty string; a path that lands
+// on a map, rather than a scalar or a list, is a validation error.
+func (pe *PermalinkExpander) attrParams(p Page, path string, args attrArgs) (string, error) {
+	var cur any = map[string]any(p.Params())
+
+	// A trailing "[slice]" split off by parseChain is walked like any
+	// other list index along the path.
+	for _, seg := range strings.Split(path+args.slice, ".") {
+		name, slice := seg, ""
+		if idx := strings.IndexByte(seg, '['); idx >= 0 {
+			if !strings.HasSuffix(seg, "]") {
+				return "", fmt.Errorf("permalinks: unterminated %q in \"params.%s\"", "[", path)
+			}
+			name, slice = seg[:idx], seg[idx:]
+		}
+
+		m, ok := toStringMap(cur)
+		if !ok {
+			return "", fmt.Errorf("permalinks: %q is not a map in \"params.%s\"", name, path)
+		}
+
+		v, found := lookupCaseInsensitive(m, name)
+		if !found || v == nil {
+			return "", nil
+		}
+
+		if slice != "" {
+			s, ok := toStringSlice(v)
+			if !ok {
+				return "", fmt.Errorf("permalinks: %q is not a list in \"params.%s\"", name, path)
+			}
+			// Slice the element indices rather than the stringified
+			// elements, so that a list of maps can be walked further.
+			idx := make([]string, len(s))
+			for k := range idx {
+				idx[k] = strconv.Itoa(k)
+			}
+			sliced := pe.toSliceFunc(slice)(idx)
+			if len(sliced) == 0 {
+				return "", nil
+			}
+			k, _ := strconv.Atoi(sliced[len(sliced)-1])
+			if items, ok := v.([]any); ok {
+				v = items[k]
+			} else {
+				v = s[k]
+			}
+		}
+
+		cur = v
+	}
+
+	if _, ok := toStringMap(cur); ok {
+		return "", fmt.Errorf("permalinks: \"params.%s\" resolves to a map, not a scalar", path)
+	}
+
+	return pe.urlize(fmt.Sprint(cur)), nil
+}
+
+// toStringMap adapts the handful of map shapes front matter parameters
+// commonly arrive in to map[string]any.
+func toStringMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			out[fmt.Sprint(k)] = v
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// toStringSlice adapts []any (the common shape for decoded front matter
+// lists) and []string to a plain []string for use with toSliceFunc.
+func toStringSlice(v any) ([]string, bool) {
+	switch s := v.(type) {
+	case []string:
+		return s, true
+	case []any:
+		out := make([]string, len(s))
+		for i, e := range s {
+			out[i] = fmt.Sprint(e)
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// lookupCaseInsensitive looks up key in m, falling back to a
+// case-insensitive scan if an exact match isn't found. Front matter keys
+// are conventionally lower-cased, but patterns shouldn't have to guess.
+func lookupCaseInsensitive(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	lower := strings.ToLower(key)
+	for k, v := range m {
+		if strings.ToLower(k) == lower {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -62,6 +62,12 @@ var testdataPermalinks = []struct {
 		p.title = "mytitle"
 		p.file = source.NewContentFileInfoFrom("/", "_index.md")
 	}, "/test-page/"},
+	// General fallback chains.
+	{"/:{slug|filename|title}/", true, nil, "/the-slug/"}, // first element already non-empty
+	{"/:{slug|filename|title}/", true, func(p *testPage) {
+		p.slug = ""
+	}, "/test-page/"}, // falls through to filename
+	{"/:{sections[last]|section}/", true, nil, "/c/"}, // chain element composes with slice syntax
 	// Unix seconds
 	{"/:unix", true, nil, "/1333681319"},
 	{"/:unixhex", true, nil, "/4f7e5ca7"},
@@ -189,6 +195,132 @@ func TestPermalinkExpansionMultiSection(t *testing.T) {
 	c.Assert(expanded, qt.Equals, "/special:the-slug")
 }
 
+func TestPermalinkExpansionChainUnknownToken(t *testing.T) {
+	t.Parallel()
+
+	c := qt.New(t)
+	expander, err := NewPermalinkExpander(urlize, nil)
+	c.Assert(err, qt.IsNil)
+
+	_, err = expander.ExpandPattern("/:{bogus|slug}/", newTestPage())
+	c.Assert(err, qt.ErrorMatches, `.*unknown attribute "bogus".*`)
+}
+
+// testdataPermalinksParams is used by TestPermalinkExpansionParams; the
+// expandsTo content is subject to the params set by newParamsPage below.
+var testdataPermalinksParams = []struct {
+	spec      string
+	valid     bool
+	expandsTo string
+}{
+	{"/:params.category/", true, "/tech/"},
+	{"/:params.Category/", true, "/tech/"}, // case-insensitive key lookup
+	{"/:params.author.name/", true, "/jane-doe/"},
+	{"/:params.tags[0]/", true, "/go/"},
+	{"/:params.tags[last]/", true, "/permalinks/"},
+	{"/:{params.missing|slug}/", true, "/the-slug/"}, // missing key falls through the chain
+	{"/:params.missing/", true, "//"},                // missing key expands to empty
+	{"/:params.author/", false, ""},                  // resolves to a map, not a scalar
+	{"/special\\::params.category/", true, "/special:tech/"}, // escape sequence ahead of a params attribute
+}
+
+func TestPermalinkExpansionParams(t *testing.T) {
+	t.Parallel()
+
+	c := qt.New(t)
+
+	newParamsPage := func() *testPage {
+		page := newTestPage()
+		page.kind = "page"
+		page.slug = "The Slug"
+		page.params = map[string]any{
+			"category": "Tech",
+			"author": map[string]any{
+				"name": "Jane Doe",
+			},
+			"tags": []any{"Go", "Hugo", "Permalinks"},
+		}
+		return page
+	}
+
+	for i, item := range testdataPermalinksParams {
+		page := newParamsPage()
+
+		specNameCleaner := regexp.MustCompile(`[\:\/\[\]{}|.]`)
+		name := fmt.Sprintf("[%d] %s", i, specNameCleaner.ReplaceAllString(item.spec, "_"))
+
+		if !item.valid {
+			c.Run(name, func(c *qt.C) {
+				expander, err := NewPermalinkExpander(urlize, nil)
+				c.Assert(err, qt.IsNil)
+				_, err = expander.ExpandPattern(item.spec, page)
+				c.Assert(err, qt.IsNotNil)
+			})
+			continue
+		}
+
+		c.Run(name, func(c *qt.C) {
+			expander, err := NewPermalinkExpander(urlize, nil)
+			c.Assert(err, qt.IsNil)
+			expanded, err := expander.ExpandPattern(item.spec, page)
+			c.Assert(err, qt.IsNil)
+			c.Assert(expanded, qt.Equals, item.expandsTo)
+		})
+	}
+}
+
+// testdataPermalinksContentHash is used by TestPermalinkExpansionContentHash;
+// it mirrors the :unixhashed cases in testdataPermalinks.
+var testdataPermalinksContentHash = []struct {
+	spec      string
+	withPage  func(p *testPage)
+	expandsTo string
+}{
+	{"/:contenthash/", nil, "/2rwmuncddi5x3nz3lvll756svrmeg4f642zlsy2atbews7ygzvba/"},
+	{"/:contenthash/", func(p *testPage) { p.content = []byte("changed") }, "/p53nvk2rpuaxacfjdttzrp7g7ggqvctyhgmqdpvqsrd4dmjrweiq/"},
+	{"/:contenthash/", func(p *testPage) { p.params["contenthashpepper"] = "A secret string!" }, "/tjys4bxzctv3vkb3wudsrug227accwlhys7ronkrmu4xojkezgeq/"},
+	{"/:contenthash:8/", nil, "/2rwmuncd/"},
+	{"/:contenthashhex/", nil, "/d46cca34431a3b7db73b5d56bff7d2ac584370bee6b2b963409849697f06cd42/"},
+	{"/:contenthashhex:8/", nil, "/d46cca34/"},
+}
+
+func TestPermalinkExpansionContentHash(t *testing.T) {
+	t.Parallel()
+
+	c := qt.New(t)
+
+	newPage := func() *testPage {
+		page := newTestPage()
+		page.kind = "page"
+		page.content = []byte("the rendered content")
+		page.params = map[string]any{}
+		return page
+	}
+
+	for i, item := range testdataPermalinksContentHash {
+		page := newPage()
+		if item.withPage != nil {
+			item.withPage(page)
+		}
+
+		name := fmt.Sprintf("[%d] %s", i, item.spec)
+		c.Run(name, func(c *qt.C) {
+			expander, err := NewPermalinkExpander(urlize, nil)
+			c.Assert(err, qt.IsNil)
+			expanded, err := expander.ExpandPattern(item.spec, page)
+			c.Assert(err, qt.IsNil)
+			c.Assert(expanded, qt.Equals, item.expandsTo)
+		})
+	}
+
+	c.Run("invalid length", func(c *qt.C) {
+		expander, err := NewPermalinkExpander(urlize, nil)
+		c.Assert(err, qt.IsNil)
+		_, err = expander.ExpandPattern("/:contenthash:0/", newPage())
+		c.Assert(err, qt.IsNotNil)
+	})
+}
+
 func TestPermalinkExpansionConcurrent(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,66 @@
+// Copyright 2024 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package page
+
+import (
+	"time"
+
+	"github.com/gohugoio/hugo/source"
+)
+
+// testPage is a minimal Page double used by this package's tests.
+type testPage struct {
+	title    string
+	date     time.Time
+	section  string
+	sections []string
+	slug     string
+	kind     string
+	file     *source.File
+	params   map[string]any
+	content  []byte
+}
+
+func newTestPage() *testPage {
+	return newTestPageWithFile("")
+}
+
+// newTestPageWithFile builds a testPage backed by the content file at
+// filename, e.g. "/test-page/index.md" for a page bundle. An empty filename
+// leaves the page fileless, as for a generated or headless page.
+func newTestPageWithFile(filename string) *testPage {
+	var f *source.File
+	if filename != "" {
+		f = source.NewContentFileInfoFrom(filename, filename)
+	}
+	return &testPage{
+		sections: []string{"a", "b", "c"},
+		kind:     "page",
+		file:     f,
+		params:   map[string]any{},
+	}
+}
+
+func (p *testPage) Title() string             { return p.title }
+func (p *testPage) Date() time.Time           { return p.date }
+func (p *testPage) Section() string           { return p.section }
+func (p *testPage) SectionsEntries() []string { return p.sections }
+func (p *testPage) Slug() string              { return p.slug }
+func (p *testPage) Kind() string              { return p.kind }
+func (p *testPage) File() *source.File        { return p.file }
+func (p *testPage) Params() map[string]any    { return p.params }
+
+func (p *testPage) ContentHashInput() ([]byte, error) {
+	return p.content, nil
+}